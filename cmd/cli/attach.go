@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Frames on the hijacked connection are a 1-byte stream id followed by a
+// 4-byte big-endian payload length, so stdout data and out-of-band
+// notifications (other clients modifying the file) can share the single
+// socket.
+const (
+	streamIDData         byte = 1
+	streamIDNotification byte = 2
+)
+
+// maxFrameSize bounds a single frame's payload length. The header's length
+// field is fully server-controlled; without a cap a buggy or malicious
+// server could make readFrames allocate up to 4GiB for one 5-byte header.
+const maxFrameSize = 16 << 20 // 16 MiB
+
+func handleStream(args []string) {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+
+	if devID == "" || fileID == "" {
+		fmt.Println("Error: --dev and --file required")
+		os.Exit(1)
+	}
+
+	var idle time.Duration
+	if timeoutStr := parseFlag(args, "--timeout"); timeoutStr != "" {
+		d, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			fmt.Printf("Error: invalid --timeout: %v\n", err)
+			os.Exit(1)
+		}
+		idle = d
+	}
+
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	conn, err := attach(ctx, devID, fileID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := readFrames(conn, idle, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "stream closed: %v\n", err)
+		}
+	}()
+
+	go io.Copy(&frameWriter{w: conn, id: streamIDData, idle: idle}, os.Stdin)
+
+	<-done
+}
+
+// attach dials the server directly and performs the HTTP handshake by hand,
+// then keeps the raw TCP connection for bidirectional framed IO -- the same
+// hijack pattern Docker's client uses for `docker attach`. ctx only bounds
+// the dial and handshake; once hijacked, the connection lives until the
+// user disconnects or the process receives SIGINT/SIGTERM.
+func attach(ctx context.Context, devID, fileID string) (net.Conn, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	useTLS := u.Scheme == "https"
+	defaultPort := "80"
+	if useTLS {
+		defaultPort = "443"
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/v1/files/%s/%s/attach", devID, fileID)
+	reqURL := "http://" + addr + path
+	if useTLS {
+		reqURL = "https://" + addr + path
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "redcloud-stream")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("server refused to hijack connection: %s", resp.Status)
+	}
+
+	return conn, nil
+}
+
+// frameWriter prefixes each write with the stream-id + length header so it
+// can be demultiplexed on the other end. idle, if non-zero, resets the
+// connection's write deadline before every frame so a server that stops
+// reading doesn't block an append forever.
+type frameWriter struct {
+	w    net.Conn
+	id   byte
+	idle time.Duration
+}
+
+func (f *frameWriter) Write(p []byte) (int, error) {
+	if f.idle > 0 {
+		f.w.SetWriteDeadline(time.Now().Add(f.idle))
+	}
+	header := make([]byte, 5)
+	header[0] = f.id
+	binary.BigEndian.PutUint32(header[1:], uint32(len(p)))
+	if _, err := f.w.Write(header); err != nil {
+		return 0, err
+	}
+	return f.w.Write(p)
+}
+
+// readFrames demultiplexes frames off conn until it closes, writing data
+// frames to stdout and notification frames to stderr. idle, if non-zero, is
+// reset as the connection's read deadline before every frame -- the same
+// read/write-deadline-per-attempt pattern the net package uses internally,
+// so a hung server shows up as an error instead of a wedged CLI.
+func readFrames(conn net.Conn, idle time.Duration, stdout, stderr io.Writer) error {
+	header := make([]byte, 5)
+	for {
+		if idle > 0 {
+			conn.SetReadDeadline(time.Now().Add(idle))
+		}
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		id := header[0]
+		length := binary.BigEndian.Uint32(header[1:])
+		if length > maxFrameSize {
+			return fmt.Errorf("frame length %d exceeds max %d", length, maxFrameSize)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return err
+		}
+		switch id {
+		case streamIDData:
+			stdout.Write(payload)
+		case streamIDNotification:
+			fmt.Fprintf(stderr, "[notify] %s\n", payload)
+		}
+	}
+}
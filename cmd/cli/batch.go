@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func handleBatch(args []string) {
+	scriptPath := parseFlag(args, "-f")
+	transactional := hasFlag(args, "--transaction")
+
+	parallel := 1
+	if p := parseFlag(args, "--parallel"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			fmt.Println("Error: --parallel must be a positive integer")
+			os.Exit(1)
+		}
+		parallel = n
+	}
+	if transactional && parallel != 1 {
+		fmt.Fprintln(os.Stderr, "Note: --transaction forces sequential execution, ignoring --parallel")
+		parallel = 1
+	}
+
+	// baseCtx only carries Ctrl-C/SIGTERM for the whole invocation; cmdTimeout
+	// bounds each dispatched command individually, the same way --timeout
+	// bounds a single request everywhere else in the CLI. Sharing one
+	// absolute deadline across an entire script/REPL session would make
+	// --timeout cut off later commands even while the server keeps
+	// responding promptly.
+	baseCtx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	cmdTimeout := parseTimeoutFlag(args)
+
+	// A single shared client, reused for every command in the script, keeps
+	// one pooled keep-alive connection to the server instead of paying
+	// TCP+TLS setup per invocation.
+	client := &http.Client{}
+
+	if scriptPath == "" {
+		runBatchREPL(baseCtx, cmdTimeout, client, transactional)
+		return
+	}
+
+	data, err := os.ReadFile(scriptPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var commands []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+
+	if transactional {
+		runBatchTransaction(baseCtx, cmdTimeout, client, commands)
+		return
+	}
+	runBatchParallel(baseCtx, cmdTimeout, client, commands, parallel)
+}
+
+// runBatchCommand builds a fresh per-command context from baseCtx before
+// dispatching, so cmdTimeout bounds this one command instead of the whole
+// batch session.
+func runBatchCommand(baseCtx context.Context, cmdTimeout time.Duration, client *http.Client, cmd string, cmdArgs []string) (interface{}, error) {
+	ctx, cancel := withChunkTimeout(baseCtx, cmdTimeout)
+	defer cancel()
+	return executeBatchCommand(ctx, client, cmd, cmdArgs)
+}
+
+// runBatchREPL drives an interactive session when stdin is a TTY (or any
+// other source the caller wants to feed line by line).
+func runBatchREPL(baseCtx context.Context, cmdTimeout time.Duration, client *http.Client, transactional bool) {
+	var compensations []func(context.Context, *http.Client)
+	createdFiles := map[string]bool{}
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Fprint(os.Stderr, "> ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			fmt.Fprint(os.Stderr, "> ")
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			break
+		}
+
+		cmd, cmdArgs := splitCommandLine(line)
+
+		if transactional {
+			if err := checkWriteCoverage(cmd, cmdArgs, createdFiles); err != nil {
+				printBatchResult(line, cmd, nil, err)
+				rollback(baseCtx, cmdTimeout, client, compensations)
+				compensations = nil
+				fmt.Fprint(os.Stderr, "> ")
+				continue
+			}
+		}
+
+		result, err := runBatchCommand(baseCtx, cmdTimeout, client, cmd, cmdArgs)
+		printBatchResult(line, cmd, result, err)
+
+		if transactional {
+			if err != nil {
+				rollback(baseCtx, cmdTimeout, client, compensations)
+				compensations = nil
+			} else {
+				trackCreatedFile(cmd, cmdArgs, result, createdFiles)
+				if c := compensationFor(cmd, cmdArgs, result); c != nil {
+					compensations = append(compensations, c)
+				}
+			}
+		}
+		fmt.Fprint(os.Stderr, "> ")
+	}
+}
+
+// runBatchTransaction executes commands sequentially, issuing compensating
+// deletes for everything done so far as soon as one step fails.
+func runBatchTransaction(baseCtx context.Context, cmdTimeout time.Duration, client *http.Client, commands []string) {
+	var compensations []func(context.Context, *http.Client)
+	createdFiles := map[string]bool{}
+	for _, line := range commands {
+		cmd, cmdArgs := splitCommandLine(line)
+
+		if err := checkWriteCoverage(cmd, cmdArgs, createdFiles); err != nil {
+			printBatchResult(line, cmd, nil, err)
+			fmt.Fprintf(os.Stderr, "transaction failed at %q, rolling back %d step(s)\n", line, len(compensations))
+			rollback(baseCtx, cmdTimeout, client, compensations)
+			os.Exit(1)
+		}
+
+		result, err := runBatchCommand(baseCtx, cmdTimeout, client, cmd, cmdArgs)
+		printBatchResult(line, cmd, result, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "transaction failed at %q, rolling back %d step(s)\n", line, len(compensations))
+			rollback(baseCtx, cmdTimeout, client, compensations)
+			os.Exit(1)
+		}
+		trackCreatedFile(cmd, cmdArgs, result, createdFiles)
+		if c := compensationFor(cmd, cmdArgs, result); c != nil {
+			compensations = append(compensations, c)
+		}
+	}
+}
+
+// trackCreatedFile records a successful create's device/file pair so a
+// later write in the same transaction can be recognized as coverable.
+func trackCreatedFile(cmd string, args []string, result interface{}, createdFiles map[string]bool) {
+	if cmd != "create" {
+		return
+	}
+	fileID, ok := result.(map[string]uint64)
+	if !ok {
+		return
+	}
+	dev := parseFlag(args, "--dev")
+	createdFiles[fileKey(dev, strconv.FormatUint(fileID["file_id"], 10))] = true
+}
+
+// rollback runs each compensation in reverse order, giving every one its own
+// fresh cmdTimeout-bounded context rather than reusing a single deadline
+// across the whole rollback.
+func rollback(baseCtx context.Context, cmdTimeout time.Duration, client *http.Client, compensations []func(context.Context, *http.Client)) {
+	for i := len(compensations) - 1; i >= 0; i-- {
+		ctx, cancel := withChunkTimeout(baseCtx, cmdTimeout)
+		compensations[i](ctx, client)
+		cancel()
+	}
+}
+
+// runBatchParallel dispatches independent commands across a bounded worker
+// pool; results are printed as they complete, so ordering in the output
+// reflects completion order rather than script order.
+func runBatchParallel(baseCtx context.Context, cmdTimeout time.Duration, client *http.Client, commands []string, parallel int) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var printMu sync.Mutex
+
+	for _, line := range commands {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(line string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cmd, cmdArgs := splitCommandLine(line)
+			result, err := runBatchCommand(baseCtx, cmdTimeout, client, cmd, cmdArgs)
+
+			printMu.Lock()
+			printBatchResult(line, cmd, result, err)
+			printMu.Unlock()
+		}(line)
+	}
+	wg.Wait()
+}
+
+func splitCommandLine(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+type batchResult struct {
+	Line    string      `json:"line"`
+	Command string      `json:"command"`
+	OK      bool        `json:"ok"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func printBatchResult(line, cmd string, result interface{}, err error) {
+	r := batchResult{Line: line, Command: cmd, OK: err == nil, Result: result}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+}
+
+// executeBatchCommand runs one scripted command against the shared client,
+// returning a structured result instead of printing and exiting like the
+// top-level handle* functions -- a failure in one line must not kill the
+// whole batch.
+func executeBatchCommand(ctx context.Context, client *http.Client, cmd string, args []string) (interface{}, error) {
+	switch cmd {
+	case "create":
+		return batchCreate(ctx, client, args)
+	case "delete":
+		return nil, batchDelete(ctx, client, args)
+	case "read":
+		return batchRead(ctx, client, args)
+	case "write":
+		return batchWrite(ctx, client, args)
+	case "copy":
+		return batchCopy(ctx, client, args)
+	case "tag-add":
+		return nil, batchTagAdd(ctx, client, args)
+	case "tag-remove":
+		return nil, batchTagRemove(ctx, client, args)
+	case "tag-list":
+		return batchTagList(ctx, client, args)
+	case "devices":
+		return batchDevices(ctx, client)
+	case "scope-list":
+		return batchScopeList(ctx, client, args)
+	default:
+		return nil, fmt.Errorf("command %q is not supported in batch mode", cmd)
+	}
+}
+
+// compensationFor returns the rollback action for a successful step, or nil
+// if the command has no meaningful inverse. Notably this excludes "write":
+// a write has no prior content the CLI ever had a copy of, so it can only be
+// covered by --transaction when it targets a file created earlier in the
+// same transaction (see checkWriteCoverage, which refuses the alternative
+// instead of silently treating an uncovered write as rolled back).
+func compensationFor(cmd string, args []string, result interface{}) func(context.Context, *http.Client) {
+	switch cmd {
+	case "create":
+		dev := parseFlag(args, "--dev")
+		fileID, ok := result.(map[string]uint64)
+		if !ok {
+			return nil
+		}
+		return func(ctx context.Context, client *http.Client) {
+			url := fmt.Sprintf("%s/v1/files/%s/%d", serverURL, dev, fileID["file_id"])
+			resp, err := doRequest(ctx, client, http.MethodDelete, url, nil)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	case "tag-add":
+		dev := parseFlag(args, "--dev")
+		file := parseFlag(args, "--file")
+		tag := parseFlag(args, "--tag")
+		return func(ctx context.Context, client *http.Client) {
+			url := fmt.Sprintf("%s/v1/tags/%s/%s?name=%s", serverURL, dev, file, tag)
+			resp, err := doRequest(ctx, client, http.MethodDelete, url, nil)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	default:
+		return nil
+	}
+}
+
+// fileKey identifies a device/file pair for transaction bookkeeping.
+func fileKey(dev, file string) string {
+	return dev + ":" + file
+}
+
+// checkWriteCoverage refuses a "write" that targets a file not created
+// earlier in the same transaction: such a write has no prior content to
+// restore, so it can't be rolled back, violating --transaction's
+// all-or-nothing guarantee for "create/write/tag-add".
+func checkWriteCoverage(cmd string, args []string, createdFiles map[string]bool) error {
+	if cmd != "write" {
+		return nil
+	}
+	key := fileKey(parseFlag(args, "--dev"), parseFlag(args, "--file"))
+	if !createdFiles[key] {
+		return fmt.Errorf("write to %s is not covered by --transaction: the file wasn't created earlier in this transaction, so it can't be rolled back", key)
+	}
+	return nil
+}
+
+// batchCreate and the rest of the batch* functions below only parse the
+// scripted command's flags -- the request/response handling itself lives in
+// the do* helpers in main.go, shared with the top-level CLI commands so the
+// two can't drift apart.
+func batchCreate(ctx context.Context, client *http.Client, args []string) (map[string]uint64, error) {
+	devID := parseFlag(args, "--dev")
+	if devID == "" {
+		return nil, fmt.Errorf("--dev required")
+	}
+	dev, _ := strconv.ParseUint(devID, 10, 64)
+	return doCreate(ctx, client, dev)
+}
+
+func batchDelete(ctx context.Context, client *http.Client, args []string) error {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	if devID == "" || fileID == "" {
+		return fmt.Errorf("--dev and --file required")
+	}
+	return doDelete(ctx, client, devID, fileID)
+}
+
+func batchRead(ctx context.Context, client *http.Client, args []string) (string, error) {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	if devID == "" || fileID == "" {
+		return "", fmt.Errorf("--dev and --file required")
+	}
+	off := parseFlag(args, "--off")
+	length := parseFlag(args, "--len")
+	return doRead(ctx, client, devID, fileID, off, length)
+}
+
+func batchWrite(ctx context.Context, client *http.Client, args []string) (map[string]int, error) {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	data := parseFlag(args, "--data")
+	if devID == "" || fileID == "" || data == "" {
+		return nil, fmt.Errorf("--dev, --file, and --data required")
+	}
+	off := parseFlag(args, "--off")
+	return doWrite(ctx, client, devID, fileID, data, off)
+}
+
+func batchCopy(ctx context.Context, client *http.Client, args []string) (map[string]uint64, error) {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	dstID := parseFlag(args, "--dst")
+	if devID == "" || fileID == "" || dstID == "" {
+		return nil, fmt.Errorf("--dev, --file, and --dst required")
+	}
+	return doCopy(ctx, client, devID, fileID, dstID)
+}
+
+func batchTagAdd(ctx context.Context, client *http.Client, args []string) error {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	tag := parseFlag(args, "--tag")
+	if devID == "" || fileID == "" || tag == "" {
+		return fmt.Errorf("--dev, --file, and --tag required")
+	}
+	return doTagAdd(ctx, client, devID, fileID, tag)
+}
+
+func batchTagRemove(ctx context.Context, client *http.Client, args []string) error {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	tag := parseFlag(args, "--tag")
+	if devID == "" || fileID == "" || tag == "" {
+		return fmt.Errorf("--dev, --file, and --tag required")
+	}
+	return doTagRemove(ctx, client, devID, fileID, tag)
+}
+
+func batchTagList(ctx context.Context, client *http.Client, args []string) ([]string, error) {
+	devID := parseFlag(args, "--dev")
+	fileID := parseFlag(args, "--file")
+	if devID == "" || fileID == "" {
+		return nil, fmt.Errorf("--dev and --file required")
+	}
+	return doTagList(ctx, client, devID, fileID)
+}
+
+func batchDevices(ctx context.Context, client *http.Client) ([]uint64, error) {
+	return doDevices(ctx, client)
+}
+
+func batchScopeList(ctx context.Context, client *http.Client, args []string) (interface{}, error) {
+	scopeID := parseFlag(args, "--scope")
+	if scopeID == "" {
+		return nil, fmt.Errorf("--scope required")
+	}
+	return doScopeList(ctx, client, scopeID)
+}
+
+func errorFromBody(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("%s", string(body))
+}
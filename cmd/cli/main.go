@@ -2,13 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 var serverURL string
@@ -43,6 +48,8 @@ func main() {
 		handleImport(os.Args[2:])
 	case "export":
 		handleExport(os.Args[2:])
+	case "stream":
+		handleStream(os.Args[2:])
 	case "tag-add":
 		handleTagAdd(os.Args[2:])
 	case "tag-remove":
@@ -59,6 +66,10 @@ func main() {
 		handleScopeAddFilter(os.Args[2:])
 	case "scope-list":
 		handleScopeList(os.Args[2:])
+	case "scope-export":
+		handleScopeExport(os.Args[2:])
+	case "batch":
+		handleBatch(os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -74,8 +85,9 @@ func printUsage() {
 	fmt.Println("  read --dev <device_id> --file <file_id> [--off <offset>] [--len <length>]")
 	fmt.Println("  write --dev <device_id> --file <file_id> --data <data> [--off <offset>]")
 	fmt.Println("  copy --dev <device_id> --file <file_id> --dst <dest_device_id>")
-	fmt.Println("  import --dev <device_id> --path <os_file_path> [--tags <tag1,tag2,...>]")
-	fmt.Println("  export --dev <device_id> --file <file_id> --path <os_file_path>")
+	fmt.Println("  import --dev <device_id> --path <os_file_path> [--tags <tag1,tag2,...>] [--silent|--no-progress]")
+	fmt.Println("  export --dev <device_id> --file <file_id> --path <os_file_path> [--silent|--no-progress]")
+	fmt.Println("  stream --dev <device_id> --file <file_id>  (attach stdin/stdout to a live file handle)")
 	fmt.Println("\nTag operations:")
 	fmt.Println("  tag-add --dev <device_id> --file <file_id> --tag <tag_name>")
 	fmt.Println("  tag-remove --dev <device_id> --file <file_id> --tag <tag_name>")
@@ -87,6 +99,15 @@ func printUsage() {
 	fmt.Println("  scope-add-source --scope <scope_id> --source <source_id>")
 	fmt.Println("  scope-add-filter --scope <scope_id> --tags <tag1,tag2,...>")
 	fmt.Println("  scope-list --scope <scope_id>")
+	fmt.Println("  scope-export --scope <scope_id> [--format tar|zip] [--out <path>|-] [--filter-tag <tag1,tag2,...>] [--silent|--no-progress]")
+	fmt.Println("\nBatch operations:")
+	fmt.Println("  batch [-f <script.txt>] [--parallel <n>] [--transaction]  (reads commands from a file, or a REPL if -f is omitted)")
+	fmt.Println("\nGlobal flags (valid on any command):")
+	fmt.Println("  --timeout <duration>  abort if the in-flight operation hasn't finished in time (e.g. 10s, 500ms)")
+	fmt.Println("    - for most commands: a deadline on the single request")
+	fmt.Println("    - for import/export and batch: a deadline on each chunk/command, not the whole run")
+	fmt.Println("    - for stream: an idle timeout, reset on every frame sent or received")
+	fmt.Println("  Ctrl-C (SIGINT) and SIGTERM also abort the in-flight request")
 }
 
 func handleCreate(args []string) {
@@ -96,25 +117,37 @@ func handleCreate(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
 	dev, _ := strconv.ParseUint(devID, 10, 64)
 
-	reqBody, _ := json.Marshal(map[string]uint64{"device_id": dev})
-	resp, err := http.Post(serverURL+"/v1/files", "application/json", bytes.NewBuffer(reqBody))
+	result, err := doCreate(ctx, http.DefaultClient, dev)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Created file ID: %d\n", result["file_id"])
+}
+
+// doCreate is the shared implementation behind the top-level create command
+// and batch mode's "create" command, so both stay in lockstep with the
+// server's request/response shape.
+func doCreate(ctx context.Context, client *http.Client, dev uint64) (map[string]uint64, error) {
+	reqBody, _ := json.Marshal(map[string]uint64{"device_id": dev})
+	resp, err := doJSONRequest(ctx, client, http.MethodPost, serverURL+"/v1/files", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string]uint64
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Created file ID: %d\n", result["file_id"])
+	return result, nil
 }
 
 func handleDelete(args []string) {
@@ -126,22 +159,29 @@ func handleDelete(args []string) {
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/files/%s/%s", serverURL, devID, fileID)
-	req, _ := http.NewRequest(http.MethodDelete, url, nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	if err := doDelete(ctx, http.DefaultClient, devID, fileID); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	fmt.Println("File deleted")
+}
+
+func doDelete(ctx context.Context, client *http.Client, devID, fileID string) error {
+	url := fmt.Sprintf("%s/v1/files/%s/%s", serverURL, devID, fileID)
+	resp, err := doRequest(ctx, client, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return errorFromBody(resp)
 	}
-
-	fmt.Println("File deleted")
+	return nil
 }
 
 func handleRead(args []string) {
@@ -155,6 +195,18 @@ func handleRead(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	data, err := doRead(ctx, http.DefaultClient, devID, fileID, off, length)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s", data)
+}
+
+func doRead(ctx context.Context, client *http.Client, devID, fileID, off, length string) (string, error) {
 	url := fmt.Sprintf("%s/v1/files/%s/%s?", serverURL, devID, fileID)
 	if off != "" {
 		url += fmt.Sprintf("off=%s&", off)
@@ -163,21 +215,18 @@ func handleRead(args []string) {
 		url += fmt.Sprintf("len=%s", length)
 	}
 
-	resp, err := http.Get(url)
+	resp, err := doRequest(ctx, client, http.MethodGet, url, nil)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return "", errorFromBody(resp)
 	}
 
-	data, _ := io.ReadAll(resp.Body)
-	fmt.Printf("%s", string(data))
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
 }
 
 func handleWrite(args []string) {
@@ -191,28 +240,40 @@ func handleWrite(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	result, err := doWrite(ctx, http.DefaultClient, devID, fileID, data, off)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("Error: request cancelled before any bytes were persisted")
+			os.Exit(1)
+		}
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d bytes\n", result["written"])
+}
+
+func doWrite(ctx context.Context, client *http.Client, devID, fileID, data, off string) (map[string]int, error) {
 	url := fmt.Sprintf("%s/v1/files/%s/%s?", serverURL, devID, fileID)
 	if off != "" {
 		url += fmt.Sprintf("off=%s", off)
 	}
 
-	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(data))
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doRequest(ctx, client, http.MethodPut, url, strings.NewReader(data))
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string]int
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Wrote %d bytes\n", result["written"])
+	return result, nil
 }
 
 func handleCopy(args []string) {
@@ -225,23 +286,32 @@ func handleCopy(args []string) {
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/files/%s/%s/copy?dst=%s", serverURL, devID, fileID, dstID)
-	resp, err := http.Post(url, "application/json", nil)
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	result, err := doCopy(ctx, http.DefaultClient, devID, fileID, dstID)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Copied to file ID: %d\n", result["file_id"])
+}
+
+func doCopy(ctx context.Context, client *http.Client, devID, fileID, dstID string) (map[string]uint64, error) {
+	url := fmt.Sprintf("%s/v1/files/%s/%s/copy?dst=%s", serverURL, devID, fileID, dstID)
+	resp, err := doJSONRequest(ctx, client, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string]uint64
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Copied to file ID: %d\n", result["file_id"])
+	return result, nil
 }
 
 func handleTagAdd(args []string) {
@@ -254,22 +324,30 @@ func handleTagAdd(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	if err := doTagAdd(ctx, http.DefaultClient, devID, fileID, tag); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Tag added")
+}
+
+func doTagAdd(ctx context.Context, client *http.Client, devID, fileID, tag string) error {
 	url := fmt.Sprintf("%s/v1/tags/%s/%s", serverURL, devID, fileID)
 	reqBody, _ := json.Marshal(map[string]string{"name": tag})
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	resp, err := doJSONRequest(ctx, client, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return errorFromBody(resp)
 	}
-
-	fmt.Println("Tag added")
+	return nil
 }
 
 func handleTagRemove(args []string) {
@@ -282,22 +360,29 @@ func handleTagRemove(args []string) {
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/tags/%s/%s?name=%s", serverURL, devID, fileID, tag)
-	req, _ := http.NewRequest(http.MethodDelete, url, nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	if err := doTagRemove(ctx, http.DefaultClient, devID, fileID, tag); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	fmt.Println("Tag removed")
+}
+
+func doTagRemove(ctx context.Context, client *http.Client, devID, fileID, tag string) error {
+	url := fmt.Sprintf("%s/v1/tags/%s/%s?name=%s", serverURL, devID, fileID, tag)
+	resp, err := doRequest(ctx, client, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return errorFromBody(resp)
 	}
-
-	fmt.Println("Tag removed")
+	return nil
 }
 
 func handleTagList(args []string) {
@@ -309,46 +394,67 @@ func handleTagList(args []string) {
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/tags/%s/%s", serverURL, devID, fileID)
-	resp, err := http.Get(url)
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	tags, err := doTagList(ctx, http.DefaultClient, devID, fileID)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Tags: %v\n", tags)
+}
+
+func doTagList(ctx context.Context, client *http.Client, devID, fileID string) ([]string, error) {
+	url := fmt.Sprintf("%s/v1/tags/%s/%s", serverURL, devID, fileID)
+	resp, err := doRequest(ctx, client, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string][]string
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Tags: %v\n", result["tags"])
+	return result["tags"], nil
 }
 
 func handleDevices(args []string) {
-	resp, err := http.Get(serverURL + "/v1/devices")
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	devices, err := doDevices(ctx, http.DefaultClient)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Devices: %v\n", devices)
+}
+
+func doDevices(ctx context.Context, client *http.Client) ([]uint64, error) {
+	resp, err := doRequest(ctx, client, http.MethodGet, serverURL+"/v1/devices", nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string][]uint64
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Devices: %v\n", result["devices"])
+	return result["devices"], nil
 }
 
 func handleScopeCreate(args []string) {
-	resp, err := http.Post(serverURL+"/v1/scopes", "application/json", nil)
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	resp, err := doJSONRequest(ctx, http.DefaultClient, http.MethodPost, serverURL+"/v1/scopes", nil)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -375,10 +481,13 @@ func handleScopeAddSource(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
 	source, _ := strconv.ParseUint(sourceID, 10, 64)
 	url := fmt.Sprintf("%s/v1/scopes/%s/sources", serverURL, scopeID)
 	reqBody, _ := json.Marshal(map[string]uint64{"source_id": source})
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	resp, err := doJSONRequest(ctx, http.DefaultClient, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -403,10 +512,13 @@ func handleScopeAddFilter(args []string) {
 		os.Exit(1)
 	}
 
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
 	tags := strings.Split(tagsStr, ",")
 	url := fmt.Sprintf("%s/v1/scopes/%s/filters", serverURL, scopeID)
 	reqBody, _ := json.Marshal(map[string][]string{"tags": tags})
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	resp, err := doJSONRequest(ctx, http.DefaultClient, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -430,29 +542,43 @@ func handleScopeList(args []string) {
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/scopes/%s/list", serverURL, scopeID)
-	resp, err := http.Get(url)
+	ctx, cancel := newRequestContext(args)
+	defer cancel()
+
+	files, err := doScopeList(ctx, http.DefaultClient, scopeID)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Files: %v\n", files)
+}
+
+func doScopeList(ctx context.Context, client *http.Client, scopeID string) (interface{}, error) {
+	url := fmt.Sprintf("%s/v1/scopes/%s/list", serverURL, scopeID)
+	resp, err := doRequest(ctx, client, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
-		os.Exit(1)
+		return nil, errorFromBody(resp)
 	}
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Files: %v\n", result["files"])
+	return result["files"], nil
 }
 
+// streamChunkSize is the unit the CLI uploads/downloads in, so multi-GB
+// transfers don't require buffering the whole file in memory on either end.
+const streamChunkSize = 1 << 20 // 1 MiB
+
 func handleImport(args []string) {
 	devID := parseFlag(args, "--dev")
 	path := parseFlag(args, "--path")
 	tagsStr := parseFlag(args, "--tags")
+	quiet := hasFlag(args, "--silent") || hasFlag(args, "--no-progress")
 
 	if devID == "" || path == "" {
 		fmt.Println("Error: --dev and --path required")
@@ -461,63 +587,416 @@ func handleImport(args []string) {
 
 	dev, _ := strconv.ParseUint(devID, 10, 64)
 
-	tags := []string{}
-	if tagsStr != "" {
-		tags = strings.Split(tagsStr, ",")
-	}
+	// A multi-GB transfer can legitimately take much longer than any single
+	// --timeout: ctx only carries Ctrl-C/SIGTERM, and chunkTimeout bounds
+	// each individual chunk attempt instead of the whole transfer.
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	chunkTimeout := parseTimeoutFlag(args)
 
-	reqBody, _ := json.Marshal(map[string]interface{}{
-		"device_id": dev,
-		"path":      path,
-		"tags":      tags,
-	})
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
 
-	resp, err := http.Post(serverURL+"/v1/import", "application/json", bytes.NewBuffer(reqBody))
+	info, err := f.Stat()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
+	createCtx, cancelCreate := withChunkTimeout(ctx, chunkTimeout)
+	reqBody, _ := json.Marshal(map[string]uint64{"device_id": dev})
+	resp, err := doJSONRequest(createCtx, http.DefaultClient, http.MethodPost, serverURL+"/v1/files", bytes.NewBuffer(reqBody))
+	cancelCreate()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	createBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
+		fmt.Printf("Error: %s\n", string(createBody))
 		os.Exit(1)
 	}
+	var created map[string]uint64
+	json.Unmarshal(createBody, &created)
+	fileID := created["file_id"]
 
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	fmt.Printf("Imported file '%s' with ID: %.0f\n", result["filename"], result["file_id"])
+	url := fmt.Sprintf("%s/v1/files/%d/%d/stream", serverURL, dev, fileID)
+	bar := newProgressBar(info.Size(), quiet)
+	sent, err := streamUpload(ctx, url, f, info.Size(), bar, chunkTimeout)
+	bar.finish()
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Printf("Interrupted after transferring %d bytes\n", sent)
+			os.Exit(1)
+		}
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if tagsStr != "" {
+		for _, tag := range strings.Split(tagsStr, ",") {
+			if err := addTag(ctx, dev, fileID, tag); err != nil {
+				fmt.Printf("Warning: failed to add tag %q: %v\n", tag, err)
+			}
+		}
+	}
+
+	fmt.Printf("Imported file '%s' with ID: %d\n", filepath.Base(path), fileID)
 }
 
 func handleExport(args []string) {
 	devID := parseFlag(args, "--dev")
 	fileID := parseFlag(args, "--file")
 	path := parseFlag(args, "--path")
+	quiet := hasFlag(args, "--silent") || hasFlag(args, "--no-progress")
 
 	if devID == "" || fileID == "" || path == "" {
 		fmt.Println("Error: --dev, --file, and --path required")
 		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("%s/v1/export/%s/%s", serverURL, devID, fileID)
-	reqBody, _ := json.Marshal(map[string]string{"path": path})
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	dev, _ := strconv.ParseUint(devID, 10, 64)
+	file, _ := strconv.ParseUint(fileID, 10, 64)
+
+	// As in handleImport: ctx only carries Ctrl-C/SIGTERM, and chunkTimeout
+	// bounds each chunk attempt rather than the whole, potentially
+	// multi-minute, transfer.
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	chunkTimeout := parseTimeoutFlag(args)
+
+	headURL := fmt.Sprintf("%s/v1/files/%d/%d/stream", serverURL, dev, file)
+	headCtx, cancelHead := withChunkTimeout(ctx, chunkTimeout)
+	headResp, err := doRequest(headCtx, http.DefaultClient, http.MethodHead, headURL, nil)
+	cancelHead()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
+	headResp.Body.Close()
+	if headResp.StatusCode != http.StatusOK {
+		fmt.Printf("Error: server returned %s for %s\n", headResp.Status, headURL)
+		os.Exit(1)
+	}
+	total := headResp.ContentLength
+	if total < 0 {
+		fmt.Println("Error: server did not report a content length for export")
+		os.Exit(1)
+	}
 
-	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error: %s\n", string(body))
+	out, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer out.Close()
+
+	bar := newProgressBar(total, quiet)
+	written, err := streamDownload(ctx, headURL, out, total, bar, chunkTimeout)
+	bar.finish()
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Printf("Interrupted after transferring %d bytes\n", written)
+			os.Exit(1)
+		}
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("Exported file to: %s\n", path)
 }
 
+// newRequestContext builds the context every handle* function issues its
+// requests with. It honors the global --timeout flag (if present in args)
+// and is cancelled on SIGINT/SIGTERM, so a hung server can't wedge the CLI
+// forever and Ctrl-C aborts cleanly instead of leaving things half done.
+func newRequestContext(args []string) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	cancelTimeout := func() {}
+	if d := parseTimeoutFlag(args); d > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, d)
+	}
+
+	ctx, cancelSignal := withInterrupt(ctx)
+	return ctx, func() {
+		cancelSignal()
+		cancelTimeout()
+	}
+}
+
+// parseTimeoutFlag reads the global --timeout flag, returning 0 if it's
+// absent. Exits the process on a malformed duration, matching the other
+// flag-validation failures in this file.
+func parseTimeoutFlag(args []string) time.Duration {
+	timeoutStr := parseFlag(args, "--timeout")
+	if timeoutStr == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		fmt.Printf("Error: invalid --timeout: %v\n", err)
+		os.Exit(1)
+	}
+	return d
+}
+
+// withInterrupt returns a context that is cancelled on SIGINT or SIGTERM, so
+// an in-flight chunk can abort cleanly instead of leaving a half-written
+// file with no indication of how far the transfer got.
+func withInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigCh)
+	}()
+	return ctx, cancel
+}
+
+// withChunkTimeout derives a context bounded by d from ctx, or returns ctx
+// unchanged (with a no-op cancel) when d is zero. Used so a single chunk
+// upload/download attempt gets its own deadline instead of one deadline
+// being shared across an entire multi-chunk transfer.
+func withChunkTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// doRequest issues req.Method against url bound to ctx, with no implied
+// Content-Type -- used for GET/DELETE and for raw (non-JSON) bodies. client
+// is explicit (rather than always http.DefaultClient) so batch mode can
+// reuse one keep-alive client across many commands.
+func doRequest(ctx context.Context, client *http.Client, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// doJSONRequest is doRequest plus an application/json Content-Type, mirroring
+// what http.Post used to set automatically.
+func doJSONRequest(ctx context.Context, client *http.Client, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return client.Do(req)
+}
+
+// streamUpload PUTs f to url in streamChunkSize chunks using Content-Range,
+// retrying a failed chunk a few times before giving up. It returns the
+// number of bytes successfully acknowledged by the server. chunkTimeout, if
+// non-zero, bounds each chunk attempt rather than the whole transfer, so
+// --timeout doesn't wedge a multi-minute upload at the first slow chunk.
+func streamUpload(ctx context.Context, url string, f *os.File, total int64, bar *progressBar, chunkTimeout time.Duration) (int64, error) {
+	const maxRetries = 5
+
+	buf := make([]byte, streamChunkSize)
+	var offset int64
+	for offset < total {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return offset, err
+		}
+		chunk := buf[:n]
+		end := offset + int64(n) - 1
+
+		var lastErr error
+		for attempt := 0; attempt < maxRetries; attempt++ {
+			if ctx.Err() != nil {
+				return offset, ctx.Err()
+			}
+			attemptCtx, cancelAttempt := withChunkTimeout(ctx, chunkTimeout)
+			req, err := http.NewRequestWithContext(attemptCtx, http.MethodPut, url, bytes.NewReader(chunk))
+			if err != nil {
+				cancelAttempt()
+				return offset, err
+			}
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end, total))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				cancelAttempt()
+				lastErr = err
+				continue
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			cancelAttempt()
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+				lastErr = fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+				continue
+			}
+			lastErr = nil
+			break
+		}
+		if lastErr != nil {
+			return offset, lastErr
+		}
+
+		offset += int64(n)
+		bar.update(offset)
+	}
+	return offset, nil
+}
+
+// streamDownload GETs url in streamChunkSize chunks using Range, retrying a
+// failed chunk a few times and resuming from the last acknowledged offset.
+// chunkTimeout, if non-zero, bounds each chunk attempt rather than the whole
+// transfer, so --timeout doesn't wedge a multi-minute download at the first
+// slow chunk.
+func streamDownload(ctx context.Context, url string, out *os.File, total int64, bar *progressBar, chunkTimeout time.Duration) (int64, error) {
+	const maxRetries = 5
+
+	var offset int64
+	for offset < total {
+		end := offset + streamChunkSize - 1
+		if end > total-1 {
+			end = total - 1
+		}
+
+		var lastErr error
+		for attempt := 0; attempt < maxRetries; attempt++ {
+			if ctx.Err() != nil {
+				return offset, ctx.Err()
+			}
+			attemptCtx, cancelAttempt := withChunkTimeout(ctx, chunkTimeout)
+			req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+			if err != nil {
+				cancelAttempt()
+				return offset, err
+			}
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				cancelAttempt()
+				lastErr = err
+				continue
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			cancelAttempt()
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+				lastErr = fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+				continue
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if _, err := out.WriteAt(body, offset); err != nil {
+				return offset, err
+			}
+			lastErr = nil
+			offset += int64(len(body))
+			break
+		}
+		if lastErr != nil {
+			return offset, lastErr
+		}
+
+		bar.update(offset)
+	}
+	return offset, nil
+}
+
+// progressBar renders byte-based transfer progress to stderr, throttled so
+// it doesn't flood the terminal on fast local links.
+type progressBar struct {
+	total     int64
+	start     time.Time
+	lastPrint time.Time
+	silent    bool
+}
+
+func newProgressBar(total int64, silent bool) *progressBar {
+	return &progressBar{total: total, start: time.Now(), silent: silent}
+}
+
+func (p *progressBar) update(transferred int64) {
+	if p.silent {
+		return
+	}
+	now := time.Now()
+	if transferred < p.total && now.Sub(p.lastPrint) < 100*time.Millisecond {
+		return
+	}
+	p.lastPrint = now
+
+	elapsed := now.Sub(p.start).Seconds()
+	if elapsed < 0.001 {
+		elapsed = 0.001
+	}
+	speed := int64(float64(transferred) / elapsed)
+
+	pct := 0.0
+	if p.total > 0 {
+		pct = float64(transferred) / float64(p.total) * 100
+	}
+	fmt.Fprintf(os.Stderr, "\r%6.2f%%  %s / %s  %s/s", pct, formatBytes(transferred), formatBytes(p.total), formatBytes(speed))
+}
+
+func (p *progressBar) finish() {
+	if p.silent {
+		return
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// addTag adds a single tag to a file and reports the server error, if any,
+// without exiting the process — used where tagging is a secondary step of a
+// larger operation (e.g. import).
+func addTag(ctx context.Context, dev, file uint64, tag string) error {
+	url := fmt.Sprintf("%s/v1/tags/%d/%d", serverURL, dev, file)
+	reqBody, _ := json.Marshal(map[string]string{"name": tag})
+	resp, err := doJSONRequest(ctx, http.DefaultClient, http.MethodPost, url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s", string(body))
+	}
+	return nil
+}
+
 func parseFlag(args []string, flag string) string {
 	for i, arg := range args {
 		if arg == flag && i+1 < len(args) {